@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"strings"
 
@@ -8,8 +9,8 @@ import (
 	"github.com/gomutex/godocx/docx"
 )
 
-func exportCoverLetterDOCX(payload CoverLetterPayload) ([]byte, string, error) {
-	doc, err := renderCoverLetterDOCX(payload)
+func exportCoverLetterDOCX(ctx context.Context, payload CoverLetterPayload) ([]byte, string, error) {
+	doc, err := renderCoverLetterDOCX(ctx, payload)
 	if err != nil {
 		return nil, "", err
 	}
@@ -30,7 +31,7 @@ func exportCoverLetterDOCX(payload CoverLetterPayload) ([]byte, string, error) {
 	return data, docxContentType, nil
 }
 
-func renderCoverLetterDOCX(payload CoverLetterPayload) (*docx.RootDoc, error) {
+func renderCoverLetterDOCX(ctx context.Context, payload CoverLetterPayload) (*docx.RootDoc, error) {
 	doc, err := godocx.NewDocument()
 	if err != nil {
 		return nil, err
@@ -57,6 +58,9 @@ func renderCoverLetterDOCX(payload CoverLetterPayload) (*docx.RootDoc, error) {
 	}
 
 	for _, para := range payload.Paragraphs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		para = strings.TrimSpace(para)
 		if para != "" {
 			doc.AddParagraph(para).Style("Normal")
@@ -2,20 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"strings"
 
 	"github.com/jung-kurt/gofpdf/v2"
 )
 
-func exportCoverLetterPDF(payload CoverLetterPayload) ([]byte, string, error) {
+func exportCoverLetterPDF(ctx context.Context, payload CoverLetterPayload) ([]byte, string, error) {
 	var buf bytes.Buffer
-	if err := renderCoverLetterPDF(payload, &buf); err != nil {
+	if err := renderCoverLetterPDF(ctx, payload, &buf); err != nil {
 		return nil, "", err
 	}
 	return buf.Bytes(), "application/pdf", nil
 }
 
-func renderCoverLetterPDF(payload CoverLetterPayload, w *bytes.Buffer) error {
+func renderCoverLetterPDF(ctx context.Context, payload CoverLetterPayload, w *bytes.Buffer) error {
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.SetMargins(marginMM, marginMM, marginMM)
 	pdf.SetAutoPageBreak(true, marginMM)
@@ -45,6 +46,9 @@ func renderCoverLetterPDF(payload CoverLetterPayload, w *bytes.Buffer) error {
 	pdf.Ln(4)
 
 	for _, p := range payload.Paragraphs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		p = strings.TrimSpace(p)
 		if p == "" {
 			continue
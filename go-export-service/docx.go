@@ -1,30 +1,35 @@
 package main
 
 import (
+	"context"
 	"os"
 	"strings"
 
 	"github.com/gomutex/godocx"
 	"github.com/gomutex/godocx/docx"
+	"github.com/gomutex/godocx/wml/stypes"
 )
 
 const docxContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
 
-func exportDOCX(payload ExportPayload) ([]byte, string, error) {
+func exportDOCX(ctx context.Context, payload ExportPayload) ([]byte, string, error) {
 	tpl := getTemplate(payload)
 	var doc *docx.RootDoc
 	var err error
 	switch tpl {
 	case "modern":
-		doc, err = renderDOCXModern(payload)
+		doc, err = renderDOCXModern(ctx, payload)
 	case "minimal":
-		doc, err = renderDOCXMinimal(payload)
+		doc, err = renderDOCXMinimal(ctx, payload)
 	default:
-		doc, err = renderDOCXClassic(payload)
+		doc, err = renderDOCXClassic(ctx, payload)
 	}
 	if err != nil {
 		return nil, "", err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
 	tmp, err := os.CreateTemp("", "landit-*.docx")
 	if err != nil {
 		return nil, "", err
@@ -42,7 +47,13 @@ func exportDOCX(payload ExportPayload) ([]byte, string, error) {
 	return data, docxContentType, nil
 }
 
-func renderDOCXClassic(payload ExportPayload) (*docx.RootDoc, error) {
+func renderDOCXClassic(ctx context.Context, payload ExportPayload) (*docx.RootDoc, error) {
+	return renderDOCX(ctx, payload, "")
+}
+
+// renderDOCX builds the shared single-column document. headingColor is a
+// six-digit hex colour for Heading 1 runs, or "" to keep the style default.
+func renderDOCX(ctx context.Context, payload ExportPayload, headingColor string) (*docx.RootDoc, error) {
 	doc, err := godocx.NewDocument()
 	if err != nil {
 		return nil, err
@@ -51,8 +62,7 @@ func renderDOCXClassic(payload ExportPayload) (*docx.RootDoc, error) {
 	pi := payload.PersonalInfo
 	name := strings.TrimSpace(pi.Name)
 	if name != "" {
-		p := doc.AddParagraph(name)
-		p.Style("Heading 1")
+		docxHeading(doc, name, headingColor)
 	}
 	contactParts := []string{}
 	if pi.Email != "" {
@@ -68,84 +78,160 @@ func renderDOCXClassic(payload ExportPayload) (*docx.RootDoc, error) {
 		doc.AddParagraph(strings.Join(contactParts, " | ")).Style("Normal")
 	}
 
-	if payload.Summary != "" {
-		doc.AddParagraph("Summary").Style("Heading 1")
-		doc.AddParagraph(payload.Summary).Style("Normal")
-	}
-
-	if len(payload.WorkExperience) > 0 {
-		doc.AddParagraph("Work Experience").Style("Heading 1")
-		for _, exp := range payload.WorkExperience {
-			titleCompany := strings.TrimSpace(exp.Title)
-			if exp.Company != "" {
-				titleCompany += " at " + strings.TrimSpace(exp.Company)
+	if err := renderSections(ctx, payload.Metadata, sectionRenderers{
+		objective: func() {
+			if objective := objectiveText(payload); objective != "" {
+				docxHeading(doc, "Objective", headingColor)
+				doc.AddParagraph(objective).Style("Normal")
 			}
-			doc.AddParagraph(titleCompany).Style("Normal")
-			dateStr := exp.StartDate
-			if exp.EndDate != "" {
-				dateStr += " - " + exp.EndDate
+		},
+		summary: func() {
+			if summary := summaryText(payload); summary != "" {
+				docxHeading(doc, "Summary", headingColor)
+				p := doc.AddEmptyParagraph()
+				p.Style("Normal")
+				addInlineMarkdown(p, summary)
 			}
-			if dateStr != "" {
-				doc.AddParagraph(dateStr).Style("Normal")
-			}
-			for _, b := range exp.Bullets {
-				if b != "" {
-					doc.AddParagraph(b).Style("List Bullet")
+		},
+		experience: func() {
+			if len(payload.WorkExperience) > 0 {
+				docxHeading(doc, "Work Experience", headingColor)
+				for _, exp := range payload.WorkExperience {
+					if ctx.Err() != nil {
+						return
+					}
+					titleCompany := strings.TrimSpace(exp.Title)
+					if exp.Company != "" {
+						titleCompany += " at " + strings.TrimSpace(exp.Company)
+					}
+					doc.AddParagraph(titleCompany).Style("Normal")
+					dateStr := exp.StartDate
+					if exp.EndDate != "" {
+						dateStr += " - " + exp.EndDate
+					}
+					if dateStr != "" {
+						doc.AddParagraph(dateStr).Style("Normal")
+					}
+					for _, b := range exp.Bullets {
+						if ctx.Err() != nil {
+							return
+						}
+						if b != "" {
+							p := doc.AddEmptyParagraph()
+							p.Style("List Bullet")
+							addInlineMarkdown(p, b)
+						}
+					}
 				}
 			}
-		}
-	}
-
-	if len(payload.Education) > 0 {
-		doc.AddParagraph("Education").Style("Heading 1")
-		for _, edu := range payload.Education {
-			line := strings.TrimSpace(edu.Degree)
-			if edu.Field != "" {
-				line += " in " + strings.TrimSpace(edu.Field)
-			}
-			if edu.School != "" {
-				line += ", " + strings.TrimSpace(edu.School)
-			}
-			if line != "" {
-				doc.AddParagraph(line).Style("Normal")
-			}
-		}
-	}
-
-	if len(payload.Skills) > 0 {
-		doc.AddParagraph("Skills").Style("Heading 1")
-		for cat, skills := range payload.Skills {
-			if cat == "" {
-				cat = "Other"
+		},
+		education: func() {
+			if len(payload.Education) > 0 {
+				docxHeading(doc, "Education", headingColor)
+				for _, edu := range payload.Education {
+					line := strings.TrimSpace(edu.Degree)
+					if edu.Field != "" {
+						line += " in " + strings.TrimSpace(edu.Field)
+					}
+					if edu.School != "" {
+						line += ", " + strings.TrimSpace(edu.School)
+					}
+					if line != "" {
+						doc.AddParagraph(line).Style("Normal")
+					}
+				}
 			}
-			var parts []string
-			for _, s := range skills {
-				if s != "" {
-					parts = append(parts, strings.TrimSpace(s))
+		},
+		skills: func() {
+			topSkills := topSkillsList(payload)
+			if len(payload.Skills) > 0 || len(topSkills) > 0 {
+				docxHeading(doc, "Skills", headingColor)
+				if len(topSkills) > 0 {
+					switch {
+					case payload.Metadata.ATSMode:
+						doc.AddParagraph("Top Skills: " + strings.Join(topSkills, ", ")).Style("Normal")
+					case headingColor != "":
+						p := doc.AddEmptyParagraph()
+						p.Style("Normal")
+						for i, s := range topSkills {
+							if i > 0 {
+								p.AddText("  ")
+							}
+							p.AddText(" "+s+" ").Bold(true).Size(12).Color("FFFFFF").Shading(stypes.ShdClear, "auto", headingColor)
+						}
+					default:
+						p := doc.AddEmptyParagraph()
+						p.Style("Normal")
+						p.AddText(strings.Join(topSkills, ", ")).Bold(true)
+					}
+				}
+				for cat, skills := range payload.Skills {
+					if cat == "" {
+						cat = "Other"
+					}
+					var parts []string
+					for _, s := range skills {
+						if s != "" {
+							parts = append(parts, strings.TrimSpace(s))
+						}
+					}
+					if len(parts) > 0 {
+						doc.AddParagraph(cat + ": " + strings.Join(parts, ", ")).Style("Normal")
+					}
 				}
 			}
-			if len(parts) > 0 {
-				doc.AddParagraph(cat+": "+strings.Join(parts, ", ")).Style("Normal")
+		},
+		certifications: func() {
+			if len(payload.Certifications) > 0 {
+				docxHeading(doc, "Certifications", headingColor)
+				for _, c := range payload.Certifications {
+					if c != "" {
+						doc.AddParagraph(strings.TrimSpace(c)).Style("List Bullet")
+					}
+				}
 			}
-		}
+		},
+	}); err != nil {
+		return nil, err
 	}
+	return doc, nil
+}
 
-	if len(payload.Certifications) > 0 {
-		doc.AddParagraph("Certifications").Style("Heading 1")
-		for _, c := range payload.Certifications {
-			if c != "" {
-				doc.AddParagraph(strings.TrimSpace(c)).Style("List Bullet")
-			}
+// addInlineMarkdown appends s to p as one run per Markdown span. Code spans
+// are highlighted since the template has no monospace character style.
+func addInlineMarkdown(p *docx.Paragraph, s string) {
+	for _, span := range parseInlineMarkdown(s) {
+		run := p.AddText(span.Text)
+		if span.Bold {
+			run.Bold(true)
+		}
+		if span.Italic {
+			run.Italic(true)
+		}
+		if span.Code {
+			run.Highlight("lightGray")
 		}
 	}
+}
 
-	return doc, nil
+func renderDOCXModern(ctx context.Context, payload ExportPayload) (*docx.RootDoc, error) {
+	headingColor := ""
+	if !payload.Metadata.ATSMode {
+		headingColor = accentColor(payload.Metadata).Hex()
+	}
+	return renderDOCX(ctx, payload, headingColor)
 }
 
-func renderDOCXModern(payload ExportPayload) (*docx.RootDoc, error) {
-	return renderDOCXClassic(payload)
+// docxHeading adds a Heading 1 paragraph, colouring its run when color is set.
+func docxHeading(doc *docx.RootDoc, text, color string) {
+	p := doc.AddEmptyParagraph()
+	p.Style("Heading 1")
+	run := p.AddText(text)
+	if color != "" {
+		run.Color(color)
+	}
 }
 
-func renderDOCXMinimal(payload ExportPayload) (*docx.RootDoc, error) {
-	return renderDOCXClassic(payload)
+func renderDOCXMinimal(ctx context.Context, payload ExportPayload) (*docx.RootDoc, error) {
+	return renderDOCXClassic(ctx, payload)
 }
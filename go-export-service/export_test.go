@@ -1,12 +1,27 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/jung-kurt/gofpdf/v2"
 )
 
 func TestExportPDF(t *testing.T) {
 	p := minimalPayload()
-	data, ct, err := exportPDF(p)
+	data, ct, err := exportPDF(context.Background(), p)
 	if err != nil {
 		t.Fatalf("exportPDF: %v", err)
 	}
@@ -20,7 +35,7 @@ func TestExportPDF(t *testing.T) {
 
 func TestExportDOCX(t *testing.T) {
 	p := minimalPayload()
-	data, ct, err := exportDOCX(p)
+	data, ct, err := exportDOCX(context.Background(), p)
 	if err != nil {
 		t.Fatalf("exportDOCX: %v", err)
 	}
@@ -34,7 +49,7 @@ func TestExportDOCX(t *testing.T) {
 
 func TestExportPreview(t *testing.T) {
 	p := minimalPayload()
-	data, ct, err := exportPreview(p)
+	data, ct, err := exportPreview(context.Background(), p)
 	if err != nil {
 		t.Fatalf("exportPreview: %v", err)
 	}
@@ -49,6 +64,657 @@ func TestExportPreview(t *testing.T) {
 	}
 }
 
+func TestExportHandlerPayloadTooLarge(t *testing.T) {
+	sem := make(chan struct{}, 1)
+	h := exportHandler(sem, 64, time.Second, exportPreview)
+	body := `{"summary":"` + strings.Repeat("x", 256) + `"}`
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/export/preview", strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status: got %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if len(sem) != 0 {
+		t.Errorf("semaphore slot not released: %d held", len(sem))
+	}
+}
+
+func TestExportHandlerTimeout(t *testing.T) {
+	sem := make(chan struct{}, 1)
+	stuck := func(ctx context.Context, _ ExportPayload) ([]byte, string, error) {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return nil, "", ctx.Err()
+	}
+	h := exportHandler(sem, defaultMaxPayloadBytes, 20*time.Millisecond, stuck)
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/export/pdf", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status: got %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+	if len(sem) != 1 {
+		t.Errorf("slot should stay held while the render is still running, %d held", len(sem))
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(sem) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(sem) != 0 {
+		t.Errorf("semaphore slot not released after the render exited: %d held", len(sem))
+	}
+}
+
+// countdownContext reports DeadlineExceeded once Err has been called more
+// than n times, so tests can cancel a render partway through a section
+// without depending on wall-clock time.
+type countdownContext struct {
+	context.Context
+	n atomic.Int64
+}
+
+func (c *countdownContext) Err() error {
+	if c.n.Add(-1) < 0 {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func TestExportBailsWithinSection(t *testing.T) {
+	p := minimalPayload()
+	bullets := make([]string, 40000)
+	for i := range bullets {
+		bullets[i] = "Shipped another **feature** for the _platform_ team."
+	}
+	p.WorkExperience = []WorkExperience{{Title: "Engineer", Bullets: bullets}}
+	for name, export := range map[string]func(context.Context, ExportPayload) ([]byte, string, error){
+		"pdf": exportPDF, "docx": exportDOCX, "preview": exportPreview,
+	} {
+		// Section-level checks alone would make far fewer than 100 calls and
+		// finish the render; only per-bullet checks reach the countdown.
+		ctx := &countdownContext{Context: context.Background()}
+		ctx.n.Store(100)
+		if _, _, err := export(ctx, p); err != context.DeadlineExceeded {
+			t.Errorf("%s: got %v, want %v", name, err, context.DeadlineExceeded)
+		}
+		if extra := -ctx.n.Load(); extra > 5 {
+			t.Errorf("%s: kept rendering after cancellation (%d more checks)", name, extra)
+		}
+	}
+}
+
+func TestExportHandlerClientGone(t *testing.T) {
+	sem := make(chan struct{}, 1)
+	h := exportHandler(sem, defaultMaxPayloadBytes, time.Second, exportPreview)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/export/preview", strings.NewReader(`{}`)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code == http.StatusInternalServerError || rec.Body.Len() != 0 {
+		t.Errorf("disconnected client should get no response, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestExportPDFCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := exportPDF(ctx, minimalPayload()); err != context.Canceled {
+		t.Errorf("exportPDF with cancelled context: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestExportPreviewObjectiveAndSummary(t *testing.T) {
+	p := minimalPayload()
+	p.Objective = "Seeking an entry-level role."
+	data, _, err := exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	out := string(data)
+	obj := strings.Index(out, "Objective")
+	sum := strings.Index(out, "Summary")
+	if obj < 0 || sum < 0 {
+		t.Fatalf("expected both Objective and Summary sections, got %s", out)
+	}
+	if obj > sum {
+		t.Error("Objective should render above Summary")
+	}
+
+	p.Metadata.ObjectiveReplacesSummary = true
+	data, _, err = exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	if strings.Contains(string(data), "Summary") {
+		t.Error("Summary should be omitted when objective_replaces_summary is set")
+	}
+}
+
+func TestPDFVerticalCenter(t *testing.T) {
+	p := ExportPayload{
+		PersonalInfo: PersonalInfo{Name: "Test User", Email: "test@example.com"},
+		Summary:      "A short summary.",
+	}
+	off, err := pdfVerticalOffset(context.Background(), p, buildPDFClassic)
+	if err != nil {
+		t.Fatalf("pdfVerticalOffset: %v", err)
+	}
+	if off != 0 {
+		t.Errorf("offset without vertical_center: got %v, want 0", off)
+	}
+
+	p.Metadata.VerticalCenter = true
+	centered, err := pdfVerticalOffset(context.Background(), p, buildPDFClassic)
+	if err != nil {
+		t.Fatalf("pdfVerticalOffset: %v", err)
+	}
+	if centered <= 0 {
+		t.Errorf("offset with vertical_center: got %v, want > 0", centered)
+	}
+	pdf, err := buildCenteredPDF(context.Background(), p, buildPDFClassic)
+	if err != nil {
+		t.Fatalf("buildCenteredPDF: %v", err)
+	}
+	if pdf.PageCount() != 1 {
+		t.Errorf("centered PDF should stay on one page, got %d", pdf.PageCount())
+	}
+	top, err := buildPDFClassic(context.Background(), p, 0)
+	if err != nil {
+		t.Fatalf("buildPDFClassic: %v", err)
+	}
+	if moved := pdf.GetY() - top.GetY(); math.Abs(moved-centered) > 0.01 {
+		t.Errorf("centered content moved down %vmm, want %vmm", moved, centered)
+	}
+
+	long := p
+	long.WorkExperience = []WorkExperience{{Title: "Engineer", Bullets: make([]string, 200)}}
+	for i := range long.WorkExperience[0].Bullets {
+		long.WorkExperience[0].Bullets[i] = "Shipped another feature."
+	}
+	off, err = pdfVerticalOffset(context.Background(), long, buildPDFClassic)
+	if err != nil {
+		t.Fatalf("pdfVerticalOffset: %v", err)
+	}
+	if off != 0 {
+		t.Errorf("offset for multi-page content: got %v, want 0", off)
+	}
+
+	p.Metadata.ATSMode = true
+	off, err = pdfVerticalOffset(context.Background(), p, buildPDFClassic)
+	if err != nil {
+		t.Fatalf("pdfVerticalOffset: %v", err)
+	}
+	if off != 0 {
+		t.Errorf("offset in ATS mode: got %v, want 0", off)
+	}
+}
+
+func TestParseInlineMarkdown(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []inlineSpan
+	}{
+		{"plain text", []inlineSpan{{Text: "plain text"}}},
+		{"**Led** a team of _5_ engineers", []inlineSpan{
+			{Text: "Led", Bold: true}, {Text: " a team of "}, {Text: "5", Italic: true}, {Text: " engineers"},
+		}},
+		{"__bold__ and *it*", []inlineSpan{{Text: "bold", Bold: true}, {Text: " and "}, {Text: "it", Italic: true}}},
+		{"run `go test` now", []inlineSpan{{Text: "run "}, {Text: "go test", Code: true}, {Text: " now"}}},
+		{"**bold _both_ bold**", []inlineSpan{
+			{Text: "bold ", Bold: true}, {Text: "both", Bold: true, Italic: true}, {Text: " bold", Bold: true},
+		}},
+		{"**oops", []inlineSpan{{Text: "**oops"}}},
+		{"*oops", []inlineSpan{{Text: "*oops"}}},
+		{"`oops", []inlineSpan{{Text: "`oops"}}},
+		{"**a *b**", []inlineSpan{{Text: "a *b", Bold: true}}},
+		{"5 * 3 * 2", []inlineSpan{{Text: "5 * 3 * 2"}}},
+		{"my_var_name", []inlineSpan{{Text: "my_var_name"}}},
+		{`\*not italic\*`, []inlineSpan{{Text: "*not italic*"}}},
+		{"****", []inlineSpan{{Text: "****"}}},
+		{"***x***", []inlineSpan{{Text: "x", Bold: true, Italic: true}}},
+		{"**_x_**", []inlineSpan{{Text: "x", Bold: true, Italic: true}}},
+		{"***x** y*", []inlineSpan{{Text: "x", Bold: true, Italic: true}, {Text: " y", Italic: true}}},
+		{"**x***", []inlineSpan{{Text: "x", Bold: true}, {Text: "*"}}},
+		{"*a**b*", []inlineSpan{{Text: "a**b", Italic: true}}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := parseInlineMarkdown(tt.in)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseInlineMarkdown(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExportPreviewMarkdown(t *testing.T) {
+	p := minimalPayload()
+	p.WorkExperience[0].Bullets = []string{"**Led** <b>5</b> `go` engineers _remotely_", "**oops"}
+	data, _, err := exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{"\\u003cstrong\\u003eLed", "\\u003ccode\\u003ego", "\\u0026lt;b\\u0026gt;5", "**oops"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("preview missing %q in %s", want, out)
+		}
+	}
+
+	// The PDF only embeds a font once a span switches to it mid-bullet.
+	pdf, _, err := exportPDF(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPDF: %v", err)
+	}
+	for _, font := range []string{"/BaseFont /Helvetica-Oblique", "/BaseFont /Courier"} {
+		if !strings.Contains(string(pdf), font) {
+			t.Errorf("PDF missing %q", font)
+		}
+	}
+
+	doc, _, err := exportDOCX(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportDOCX: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(doc), int64(len(doc)))
+	if err != nil {
+		t.Fatalf("DOCX is not a zip archive: %v", err)
+	}
+	var body string
+	for _, f := range zr.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		body = string(data)
+	}
+	for _, want := range []string{
+		`<w:rPr><w:b w:val="true"></w:b></w:rPr><w:t>Led</w:t>`,
+		`<w:rPr><w:i w:val="true"></w:i></w:rPr><w:t>remotely</w:t>`,
+		"**oops",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("DOCX document.xml missing %q", want)
+		}
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want rgbColor
+		ok   bool
+	}{
+		{"#2563eb", rgbColor{0x25, 0x63, 0xeb}, true},
+		{"2563EB", rgbColor{0x25, 0x63, 0xeb}, true},
+		{"#26e", rgbColor{0x22, 0x66, 0xee}, true},
+		{"abc", rgbColor{0xaa, 0xbb, 0xcc}, true},
+		{"", rgbColor{}, false},
+		{"#12345", rgbColor{}, false},
+		{"#zzzzzz", rgbColor{}, false},
+		{"##2563eb", rgbColor{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseHexColor(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseHexColor(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestModernInvalidAccentColor(t *testing.T) {
+	p := minimalPayload()
+	p.Metadata.TemplateName = "modern"
+	p.Metadata.ATSMode = false
+	p.Metadata.AccentColor = "not-a-color"
+
+	pdf, _, err := exportPDF(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPDF: %v", err)
+	}
+	if !strings.HasPrefix(string(pdf), "%PDF-") {
+		t.Error("modern PDF with invalid accent is not a PDF")
+	}
+	doc, _, err := exportDOCX(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportDOCX: %v", err)
+	}
+	if !strings.HasPrefix(string(doc), "PK") {
+		t.Error("modern DOCX with invalid accent is not a zip archive")
+	}
+	preview, _, err := exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	if !strings.Contains(string(preview), "color:#"+accentColor(ExportMetadata{}).Hex()) {
+		t.Errorf("modern preview should fall back to the default accent, got %s", preview)
+	}
+
+	p.Metadata.ATSMode = true
+	preview, _, err = exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	if strings.Contains(string(preview), accentColor(ExportMetadata{}).Hex()) {
+		t.Error("ATS mode preview should ignore the accent colour")
+	}
+}
+
+func TestEnabledFormats(t *testing.T) {
+	enabled, err := parseEnabledFormats(" PDF, preview, png ")
+	if err != nil || !reflect.DeepEqual(enabled, []string{"pdf", "preview"}) {
+		t.Fatalf("parseEnabledFormats: got %v, %v", enabled, err)
+	}
+	if got, err := parseEnabledFormats(""); err != nil || !reflect.DeepEqual(got, exportFormats) {
+		t.Errorf("empty ENABLED_FORMATS should enable everything, got %v, %v", got, err)
+	}
+	for _, bad := range []string{"pdf;docx", "pdf docx", "png"} {
+		if got, err := parseEnabledFormats(bad); err == nil {
+			t.Errorf("parseEnabledFormats(%q) should fail, got %v", bad, got)
+		}
+	}
+
+	mux := newServeMux(make(chan struct{}, 1), defaultMaxPayloadBytes, time.Second, enabled)
+	body := `{"personal_info":{"name":"Test User"},"summary":"A short summary."}`
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/export/preview", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("enabled preview: got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/export/docx", strings.NewReader(body)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("disabled docx: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if !strings.Contains(rec.Body.String(), "not enabled") {
+		t.Errorf("disabled docx: unhelpful message %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/capabilities", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("capabilities: got %d", rec.Code)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"formats":["pdf","preview"]}` {
+		t.Errorf("capabilities: got %s", got)
+	}
+}
+
+func TestTopSkills(t *testing.T) {
+	p := minimalPayload()
+	p.Metadata.ATSMode = false
+	p.TopSkills = []string{"Go", " ", "Kubernetes", "PostgreSQL"}
+	render := func() string {
+		t.Helper()
+		data, _, err := exportPreview(context.Background(), p)
+		if err != nil {
+			t.Fatalf("exportPreview: %v", err)
+		}
+		return string(data)
+	}
+
+	out := render()
+	top := strings.Index(out, "\\u003cstrong\\u003eGo, Kubernetes, PostgreSQL\\u003c/strong\\u003e")
+	cat := strings.Index(out, "Tech: Go, Python")
+	if top < 0 || cat < 0 {
+		t.Fatalf("classic preview missing bold top skills or categories: %s", out)
+	}
+	if top > cat {
+		t.Error("top skills should render above the categorized skills")
+	}
+
+	p.Metadata.TemplateName = "modern"
+	out = render()
+	if !strings.Contains(out, "border-radius:999px;background:#2563EB") || !strings.Contains(out, "\\u003eKubernetes\\u003c/span\\u003e") {
+		t.Errorf("modern preview should render accent chips: %s", out)
+	}
+
+	p.Metadata.ATSMode = true
+	out = render()
+	if !strings.Contains(out, "Top Skills: Go, Kubernetes, PostgreSQL") || strings.Contains(out, "\\u003cstrong\\u003eGo") {
+		t.Errorf("ATS preview should use a plain labeled line: %s", out)
+	}
+
+	p.Metadata.ATSMode = false
+	p.Skills = nil
+	for i := 0; i < 30; i++ {
+		p.TopSkills = append(p.TopSkills, "Distributed Systems")
+	}
+	if _, _, err := exportPDF(context.Background(), p); err != nil {
+		t.Errorf("exportPDF with wrapped chips: %v", err)
+	}
+	if _, _, err := exportDOCX(context.Background(), p); err != nil {
+		t.Errorf("exportDOCX with top skills: %v", err)
+	}
+}
+
+func TestDrainingHandler(t *testing.T) {
+	var draining atomic.Bool
+	mux := newServeMux(make(chan struct{}, 1), defaultMaxPayloadBytes, time.Second, exportFormats)
+	h := drainingHandler(&draining, mux)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("health before shutdown: got %d", rec.Code)
+	}
+
+	draining.Store(true)
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/health", nil),
+		httptest.NewRequest(http.MethodPost, "/export/preview", strings.NewReader(`{}`)),
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s during shutdown: got %d, want %d", req.URL.Path, rec.Code, http.StatusServiceUnavailable)
+		}
+	}
+}
+
+func TestShutdownGracefully(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	sem := make(chan struct{}, 2)
+	var draining atomic.Bool
+	srv := &http.Server{Handler: drainingHandler(&draining, newServeMux(sem, defaultMaxPayloadBytes, time.Second, exportFormats))}
+	go srv.Serve(ln)
+	url := "http://" + ln.Addr().String() + "/health"
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("health before shutdown: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("health before shutdown: got %d", resp.StatusCode)
+	}
+
+	// Hold a slot as an in-flight export would, and free it partway through.
+	sem <- struct{}{}
+	const drainDelay = 200 * time.Millisecond
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- shutdownGracefully(srv, &draining, sem, drainDelay, 2*time.Second) }()
+	time.AfterFunc(2*drainDelay, func() { <-sem })
+
+	time.Sleep(drainDelay / 4)
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatalf("health during drain delay should answer, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("health during drain delay: got %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("shutdownGracefully: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*drainDelay {
+		t.Errorf("shutdown returned after %s, before the in-flight export freed its slot", elapsed)
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("server should stop accepting connections after shutdown")
+	}
+}
+
+func TestShutdownDrainDelayWithinTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	var draining atomic.Bool
+	srv := &http.Server{Handler: drainingHandler(&draining, http.NotFoundHandler())}
+	go srv.Serve(ln)
+
+	start := time.Now()
+	// The budget runs out during the drain delay, so giving up is fine too.
+	if err := shutdownGracefully(srv, &draining, make(chan struct{}, 1), time.Minute, 50*time.Millisecond); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdownGracefully: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("shutdown took %s, want the drain delay cut short by the timeout", elapsed)
+	}
+}
+
+func TestWaitForExports(t *testing.T) {
+	sem := make(chan struct{}, 2)
+	sem <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := waitForExports(ctx, sem); err != context.DeadlineExceeded {
+		t.Errorf("with a held slot: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	sem = make(chan struct{}, 2)
+	sem <- struct{}{}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-sem
+	}()
+	if err := waitForExports(context.Background(), sem); err != nil {
+		t.Errorf("after the slot frees: got %v", err)
+	}
+	if len(sem) != cap(sem) {
+		t.Errorf("waitForExports should hold every slot, holds %d of %d", len(sem), cap(sem))
+	}
+}
+
+func TestSectionOrder(t *testing.T) {
+	if got := sectionOrder(ExportMetadata{}); !reflect.DeepEqual(got, defaultSectionOrder) {
+		t.Errorf("empty section_order: got %v", got)
+	}
+	if got := sectionOrder(ExportMetadata{SectionOrder: []string{"work_experience"}}); !reflect.DeepEqual(got, defaultSectionOrder) {
+		t.Errorf("all-unknown section_order: got %v", got)
+	}
+	meta := ExportMetadata{SectionOrder: []string{"Education", "bogus", "experience", "education"}}
+	if got := sectionOrder(meta); !reflect.DeepEqual(got, []string{"education", "experience"}) {
+		t.Errorf("sectionOrder: got %v", got)
+	}
+
+	p := minimalPayload()
+	data, _, err := exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	out := string(data)
+	if strings.Index(out, "Work Experience") > strings.Index(out, "Education") {
+		t.Fatal("default order should put Work Experience above Education")
+	}
+
+	p.Metadata.SectionOrder = []string{"education", "experience", "bogus", "skills"}
+	data, _, err = exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	out = string(data)
+	edu, exp, skills := strings.Index(out, "Education"), strings.Index(out, "Work Experience"), strings.Index(out, "Skills")
+	if edu < 0 || exp < 0 || skills < 0 || !(edu < exp && exp < skills) {
+		t.Errorf("reordered sections out of order (education=%d experience=%d skills=%d)", edu, exp, skills)
+	}
+	if strings.Contains(out, "Summary") {
+		t.Error("sections omitted from section_order should not render")
+	}
+
+	for _, export := range []func(context.Context, ExportPayload) ([]byte, string, error){exportPDF, exportDOCX} {
+		if _, _, err := export(context.Background(), p); err != nil {
+			t.Errorf("export with section_order: %v", err)
+		}
+	}
+
+	// objective_replaces_summary must not drop the summary when the
+	// objective itself is left out of section_order.
+	p.Objective = "Seeking an entry-level role."
+	p.Metadata.ObjectiveReplacesSummary = true
+	p.Metadata.SectionOrder = []string{"summary", "experience"}
+	data, _, err = exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	out = string(data)
+	if !strings.Contains(out, "A short summary.") {
+		t.Error("summary should render when the objective is not in section_order")
+	}
+	if strings.Contains(out, "Seeking an entry-level role.") {
+		t.Error("objective should not render when it is not in section_order")
+	}
+
+	p.Metadata.SectionOrder = []string{"objective", "summary", "experience"}
+	data, _, err = exportPreview(context.Background(), p)
+	if err != nil {
+		t.Fatalf("exportPreview: %v", err)
+	}
+	out = string(data)
+	if strings.Contains(out, "A short summary.") || !strings.Contains(out, "Seeking an entry-level role.") {
+		t.Error("objective should replace the summary when both are in section_order")
+	}
+}
+
+func TestTopSkillsWrapInPDF(t *testing.T) {
+	long := strings.Repeat("Extremely Long Skill Name ", 12)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(marginMM, marginMM, marginMM)
+	pdf.AddPage()
+	y := pdf.GetY()
+	pdfSkillChips(pdf, rgbColor{0x25, 0x63, 0xeb}, []string{"Go", long, "SQL"})
+	// Three rows: "Go", the oversized skill wrapped over several lines, "SQL".
+	if got := pdf.GetY() - y; got < 5*6.5 {
+		t.Errorf("oversized chip should wrap, chips only advanced %.1fmm", got)
+	}
+	if err := pdf.Error(); err != nil {
+		t.Errorf("pdfSkillChips: %v", err)
+	}
+
+	p := minimalPayload()
+	p.Skills = nil
+	p.TopSkills = []string{"Go"}
+	short, err := buildPDFClassic(context.Background(), p, 0)
+	if err != nil {
+		t.Fatalf("buildPDFClassic: %v", err)
+	}
+	p.TopSkills = []string{long}
+	wrapped, err := buildPDFClassic(context.Background(), p, 0)
+	if err != nil {
+		t.Fatalf("buildPDFClassic: %v", err)
+	}
+	if wrapped.GetY() <= short.GetY() {
+		t.Error("ATS top skills line should wrap instead of running off the page")
+	}
+}
+
 func minimalPayload() ExportPayload {
 	return ExportPayload{
 		PersonalInfo: PersonalInfo{
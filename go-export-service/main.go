@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 const defaultPort = "8001"
 const defaultMaxConcurrent = 50
+const defaultMaxPayloadBytes = 1 << 20
+const defaultExportTimeout = 15 * time.Second
+const defaultShutdownTimeout = 30 * time.Second
+const defaultShutdownDrainDelay = 5 * time.Second
 
 func main() {
 	port := os.Getenv("PORT")
@@ -22,8 +34,163 @@ func main() {
 		}
 	}
 	sem := make(chan struct{}, maxConcurrent)
+	maxPayloadBytes := int64(defaultMaxPayloadBytes)
+	if v := os.Getenv("MAX_PAYLOAD_BYTES"); v != "" {
+		if n, err := parseInt(v); err == nil && n > 0 {
+			maxPayloadBytes = int64(n)
+		}
+	}
+	exportTimeout := defaultExportTimeout
+	if v := os.Getenv("EXPORT_TIMEOUT"); v != "" {
+		if d, err := parseDuration(v); err == nil && d > 0 {
+			exportTimeout = d
+		}
+	}
+
+	shutdownTimeout := defaultShutdownTimeout
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		if d, err := parseDuration(v); err == nil && d > 0 {
+			shutdownTimeout = d
+		}
+	}
+	drainDelay := defaultShutdownDrainDelay
+	if v := os.Getenv("SHUTDOWN_DRAIN_DELAY"); v != "" {
+		if d, err := parseDuration(v); err == nil && d >= 0 {
+			drainDelay = d
+		}
+	}
+	enabledFormats, err := parseEnabledFormats(os.Getenv("ENABLED_FORMATS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Enabled export formats: %s", strings.Join(enabledFormats, ", "))
+
+	mux := newServeMux(sem, maxPayloadBytes, exportTimeout, enabledFormats)
+	var draining atomic.Bool
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: drainingHandler(&draining, mux),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s", srv.Addr)
+		errc <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Printf("Shutting down within %s; answering 503 for %s before draining in-flight exports", shutdownTimeout, drainDelay)
+	if err := shutdownGracefully(srv, &draining, sem, drainDelay, shutdownTimeout); err != nil {
+		log.Printf("shutdown: %v", err)
+		return
+	}
+	log.Printf("Shutdown complete")
+}
+
+// shutdownGracefully sets draining and keeps the listener open for
+// drainDelay, so load balancer health checks see 503s rather than refused
+// connections and take us out of rotation. It then stops the server and
+// waits for in-flight requests and export renders to finish. The whole
+// shutdown, drain delay included, is bounded by timeout.
+func shutdownGracefully(srv *http.Server, draining *atomic.Bool, sem chan struct{}, drainDelay, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	draining.Store(true)
+	select {
+	case <-time.After(drainDelay):
+	case <-ctx.Done():
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := waitForExports(ctx, sem); err != nil {
+		return fmt.Errorf("gave up waiting for exports: %w", err)
+	}
+	return nil
+}
+
+// drainingHandler answers every request, /health included, with 503 once
+// draining is set so load balancers stop routing here during shutdown.
+func drainingHandler(draining *atomic.Bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if draining.Load() {
+			w.Header().Set("Connection", "close")
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// waitForExports blocks until every semaphore slot is free, or ctx ends. It
+// keeps the slots it takes, so no new export can start afterwards.
+func waitForExports(ctx context.Context, sem chan struct{}) error {
+	for i := 0; i < cap(sem); i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
 
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+// exportFormats lists every export route the service knows about, in the
+// order /capabilities reports them. Each is served at /export/<format>.
+var exportFormats = []string{"pdf", "docx", "preview", "cover-letter-pdf", "cover-letter-docx"}
+
+// parseEnabledFormats reads the comma-separated ENABLED_FORMATS value. An
+// empty value enables everything; unknown names are logged and skipped. A
+// value that names no known format at all (e.g. "pdf;docx") is an error
+// rather than a server with every export disabled.
+func parseEnabledFormats(v string) ([]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return exportFormats, nil
+	}
+	want := map[string]bool{}
+	for _, f := range strings.Split(v, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			continue
+		}
+		known := false
+		for _, k := range exportFormats {
+			if f == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			log.Printf("ENABLED_FORMATS: ignoring unknown format %q", f)
+			continue
+		}
+		want[f] = true
+	}
+	var enabled []string
+	for _, f := range exportFormats {
+		if want[f] {
+			enabled = append(enabled, f)
+		}
+	}
+	if len(enabled) == 0 {
+		return nil, fmt.Errorf("ENABLED_FORMATS=%q names no known format; expected a comma-separated list of %s", v, strings.Join(exportFormats, ", "))
+	}
+	return enabled, nil
+}
+
+// newServeMux registers /health, /capabilities and the export routes for
+// the enabled formats. Any other /export/ path answers 404 with a message
+// naming the format instead of the mux's bare "404 page not found".
+func newServeMux(sem chan struct{}, maxBytes int64, timeout time.Duration, enabled []string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
@@ -32,38 +199,73 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
-	http.HandleFunc("/export/pdf", exportHandler(sem, exportPDF))
-	http.HandleFunc("/export/docx", exportHandler(sem, exportDOCX))
-	http.HandleFunc("/export/preview", exportHandler(sem, exportPreview))
-	http.HandleFunc("/export/cover-letter-pdf", coverLetterExportHandler(sem, exportCoverLetterPDF))
-	http.HandleFunc("/export/cover-letter-docx", coverLetterExportHandler(sem, exportCoverLetterDOCX))
-
-	addr := ":" + port
-	log.Printf("Listening on %s", addr)
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		log.Fatal(err)
+	handlers := map[string]http.HandlerFunc{
+		"pdf":               exportHandler(sem, maxBytes, timeout, exportPDF),
+		"docx":              exportHandler(sem, maxBytes, timeout, exportDOCX),
+		"preview":           exportHandler(sem, maxBytes, timeout, exportPreview),
+		"cover-letter-pdf":  coverLetterExportHandler(sem, maxBytes, timeout, exportCoverLetterPDF),
+		"cover-letter-docx": coverLetterExportHandler(sem, maxBytes, timeout, exportCoverLetterDOCX),
 	}
+	for _, f := range enabled {
+		if h, ok := handlers[f]; ok {
+			mux.HandleFunc("/export/"+f, h)
+		}
+	}
+	mux.HandleFunc("/export/", func(w http.ResponseWriter, r *http.Request) {
+		format := strings.TrimPrefix(r.URL.Path, "/export/")
+		http.Error(w, fmt.Sprintf("export format %q is not enabled on this server", format), http.StatusNotFound)
+	})
+
+	mux.HandleFunc("/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		formats := enabled
+		if formats == nil {
+			formats = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"formats": formats})
+	})
+	return mux
 }
 
-func exportHandler(sem chan struct{}, fn func(ExportPayload) ([]byte, string, error)) http.HandlerFunc {
+func exportHandler(sem chan struct{}, maxBytes int64, timeout time.Duration, fn func(context.Context, ExportPayload) ([]byte, string, error)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 		var payload ExportPayload
 		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+				return
+			}
 			http.Error(w, "invalid JSON", http.StatusBadRequest)
 			return
 		}
 		select {
 		case sem <- struct{}{}:
-			defer func() { <-sem }()
 		default:
 			http.Error(w, "too many concurrent exports", http.StatusServiceUnavailable)
 			return
 		}
-		data, contentType, err := fn(payload)
+		data, contentType, err := runWithTimeout(r.Context(), timeout, func() { <-sem }, func(ctx context.Context) ([]byte, string, error) {
+			return fn(ctx, payload)
+		})
+		if errors.Is(err, context.Canceled) {
+			// The client went away; there is nobody left to answer.
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("export error: timed out after %s", timeout)
+			http.Error(w, "export timed out", http.StatusGatewayTimeout)
+			return
+		}
 		if err != nil {
 			log.Printf("export error: %v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -74,25 +276,41 @@ func exportHandler(sem chan struct{}, fn func(ExportPayload) ([]byte, string, er
 	}
 }
 
-func coverLetterExportHandler(sem chan struct{}, fn func(CoverLetterPayload) ([]byte, string, error)) http.HandlerFunc {
+func coverLetterExportHandler(sem chan struct{}, maxBytes int64, timeout time.Duration, fn func(context.Context, CoverLetterPayload) ([]byte, string, error)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 		var payload CoverLetterPayload
 		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+				return
+			}
 			http.Error(w, "invalid JSON", http.StatusBadRequest)
 			return
 		}
 		select {
 		case sem <- struct{}{}:
-			defer func() { <-sem }()
 		default:
 			http.Error(w, "too many concurrent exports", http.StatusServiceUnavailable)
 			return
 		}
-		data, contentType, err := fn(payload)
+		data, contentType, err := runWithTimeout(r.Context(), timeout, func() { <-sem }, func(ctx context.Context) ([]byte, string, error) {
+			return fn(ctx, payload)
+		})
+		if errors.Is(err, context.Canceled) {
+			// The client went away; there is nobody left to answer.
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("cover letter export error: timed out after %s", timeout)
+			http.Error(w, "export timed out", http.StatusGatewayTimeout)
+			return
+		}
 		if err != nil {
 			log.Printf("cover letter export error: %v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -103,23 +321,52 @@ func coverLetterExportHandler(sem chan struct{}, fn func(CoverLetterPayload) ([]
 	}
 }
 
+// runWithTimeout runs fn under a context that expires after timeout and
+// calls release once fn has actually returned. On timeout the deadline error
+// comes back immediately so the handler can answer 504, but the semaphore
+// slot stays held until the render notices the cancelled context and exits,
+// keeping MAX_CONCURRENT_EXPORTS a real bound on live renders.
+func runWithTimeout(parent context.Context, timeout time.Duration, release func(), fn func(context.Context) ([]byte, string, error)) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	type result struct {
+		data        []byte
+		contentType string
+		err         error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer release()
+		data, contentType, err := fn(ctx)
+		done <- result{data, contentType, err}
+	}()
+	select {
+	case res := <-done:
+		return res.data, res.contentType, res.err
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+}
+
 type ExportPayload struct {
-	PersonalInfo   PersonalInfo    `json:"personal_info"`
-	Summary        string          `json:"summary"`
-	WorkExperience []WorkExperience `json:"work_experience"`
-	Education      []Education     `json:"education"`
+	PersonalInfo   PersonalInfo        `json:"personal_info"`
+	Objective      string              `json:"objective"`
+	Summary        string              `json:"summary"`
+	WorkExperience []WorkExperience    `json:"work_experience"`
+	Education      []Education         `json:"education"`
 	Skills         map[string][]string `json:"skills"`
-	Certifications []string        `json:"certifications"`
-	Metadata       ExportMetadata  `json:"metadata"`
+	TopSkills      []string            `json:"top_skills"`
+	Certifications []string            `json:"certifications"`
+	Metadata       ExportMetadata      `json:"metadata"`
 }
 
 type PersonalInfo struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Phone    string `json:"phone"`
-	Location string `json:"location"`
-	Linkedin string `json:"linkedin"`
-	Github   string `json:"github"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	Location  string `json:"location"`
+	Linkedin  string `json:"linkedin"`
+	Github    string `json:"github"`
 	Portfolio string `json:"portfolio"`
 }
 
@@ -146,10 +393,25 @@ type ExportMetadata struct {
 	ExportFormat string `json:"export_format"`
 	ATSMode      bool   `json:"ats_mode"`
 	JobTitle     string `json:"job_title"`
+	// ObjectiveReplacesSummary drops the summary section whenever a
+	// non-blank objective is rendered in its place; otherwise both are
+	// rendered.
+	ObjectiveReplacesSummary bool `json:"objective_replaces_summary"`
+	// VerticalCenter pads sparse single-page PDFs so the content sits in
+	// the middle of the page. Ignored in ATS mode.
+	VerticalCenter bool `json:"vertical_center"`
+	// AccentColor is a hex colour ("#2563eb", "2563eb", "#26e") for the
+	// Modern template. Invalid values fall back to the default accent.
+	AccentColor string `json:"accent_color"`
+	// SectionOrder lists the section keys to render, top to bottom:
+	// "objective", "summary", "experience", "education", "skills",
+	// "certifications". Omitted sections are skipped and unknown keys are
+	// ignored; a list with no known keys keeps the default order.
+	SectionOrder []string `json:"section_order"`
 }
 
 type CoverLetterPayload struct {
-	PersonalInfo PersonalInfo         `json:"personal_info"`
+	PersonalInfo PersonalInfo        `json:"personal_info"`
 	Paragraphs   []string            `json:"paragraphs"`
 	Metadata     CoverLetterMetadata `json:"metadata"`
 }
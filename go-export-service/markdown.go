@@ -0,0 +1,203 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// inlineSpan is a run of text sharing one set of inline styles.
+type inlineSpan struct {
+	Text   string
+	Bold   bool
+	Italic bool
+	Code   bool
+}
+
+type mdToken struct {
+	text     string
+	delim    rune // '*' or '_' for a delimiter run; 0 for text
+	size     int  // length of the delimiter run as written
+	left     int  // run characters not yet paired
+	code     bool
+	canOpen  bool
+	canClose bool
+
+	opensBold, opensItalic   int
+	closesBold, closesItalic int
+}
+
+// parseInlineMarkdown splits s into styled spans, understanding **bold**,
+// __bold__, *italic*, _italic_, ***both*** and `code`. Markers that never
+// find a partner (e.g. "**oops") are kept as literal text, and a backslash
+// escapes the next marker character.
+func parseInlineMarkdown(s string) []inlineSpan {
+	tokens := tokenizeInlineMarkdown(s)
+
+	// Pair delimiter runs with a stack. A closer takes two characters (bold)
+	// or one (italic) at a time from the nearest opener of the same character,
+	// so "***" can close as bold plus italic. Closing an outer marker discards
+	// any unclosed openers above it, which then render literally.
+	var stack []int
+	for i := range tokens {
+		t := &tokens[i]
+		if t.delim == 0 {
+			continue
+		}
+		for t.canClose && t.left > 0 {
+			j := findOpener(tokens, stack, i)
+			// An empty pair like "**__" stays literal.
+			if j < 0 || stack[j] == i-1 {
+				break
+			}
+			o := &tokens[stack[j]]
+			if o.left >= 2 && t.left >= 2 {
+				o.opensBold++
+				t.closesBold++
+				o.left -= 2
+				t.left -= 2
+			} else {
+				o.opensItalic++
+				t.closesItalic++
+				o.left--
+				t.left--
+			}
+			if o.left > 0 {
+				stack = stack[:j+1]
+			} else {
+				stack = stack[:j]
+			}
+		}
+		if t.canOpen && t.left > 0 {
+			stack = append(stack, i)
+		}
+	}
+
+	var spans []inlineSpan
+	emit := func(text string, bold, italic, code bool) {
+		if text == "" {
+			return
+		}
+		if n := len(spans); n > 0 {
+			last := &spans[n-1]
+			if last.Bold == bold && last.Italic == italic && last.Code == code {
+				last.Text += text
+				return
+			}
+		}
+		spans = append(spans, inlineSpan{Text: text, Bold: bold, Italic: italic, Code: code})
+	}
+
+	var bold, italic int
+	for _, t := range tokens {
+		switch {
+		case t.code:
+			emit(t.text, bold > 0, italic > 0, true)
+		case t.delim == 0:
+			emit(t.text, bold > 0, italic > 0, false)
+		default:
+			// Unpaired characters sit outside whatever the run closes or opens.
+			bold -= t.closesBold
+			italic -= t.closesItalic
+			emit(strings.Repeat(string(t.delim), t.left), bold > 0, italic > 0, false)
+			bold += t.opensBold
+			italic += t.opensItalic
+		}
+	}
+	return spans
+}
+
+// findOpener returns the stack position of the opener that tokens[i] should
+// close, or -1. It prefers the nearest opener with enough characters left to
+// match a bold closer, so the stray "*" in "**a *b**" stays literal, and
+// follows CommonMark's rule of three for runs that can both open and close,
+// so "*a**b*" is one italic span.
+func findOpener(tokens []mdToken, stack []int, i int) int {
+	t := tokens[i]
+	fallback := -1
+	for j := len(stack) - 1; j >= 0; j-- {
+		o := tokens[stack[j]]
+		if o.delim != t.delim {
+			continue
+		}
+		if (o.canClose || t.canOpen) && (o.size+t.size)%3 == 0 && (o.size%3 != 0 || t.size%3 != 0) {
+			continue
+		}
+		if o.left >= 2 || t.left < 2 {
+			return j
+		}
+		if fallback < 0 {
+			fallback = j
+		}
+	}
+	return fallback
+}
+
+func tokenizeInlineMarkdown(s string) []mdToken {
+	runes := []rune(s)
+	var tokens []mdToken
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, mdToken{text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && strings.ContainsRune("*_`\\", runes[i+1]):
+			text.WriteRune(runes[i+1])
+			i++
+		case r == '`':
+			end := -1
+			for j := i + 1; j < len(runes); j++ {
+				if runes[j] == '`' {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				text.WriteRune(r)
+				continue
+			}
+			flush()
+			tokens = append(tokens, mdToken{text: string(runes[i+1 : end]), code: true})
+			i = end
+		case r == '*' || r == '_':
+			n := 1
+			for i+n < len(runes) && runes[i+n] == r {
+				n++
+			}
+			var prev, next rune
+			if i > 0 {
+				prev = runes[i-1]
+			}
+			if i+n < len(runes) {
+				next = runes[i+n]
+			}
+			canOpen := next != 0 && !unicode.IsSpace(next)
+			canClose := prev != 0 && !unicode.IsSpace(prev)
+			if r == '_' {
+				// Leave snake_case identifiers alone.
+				canOpen = canOpen && !isWordRune(prev)
+				canClose = canClose && !isWordRune(next)
+			}
+			if !canOpen && !canClose {
+				text.WriteString(strings.Repeat(string(r), n))
+			} else {
+				flush()
+				tokens = append(tokens, mdToken{delim: r, size: n, left: n, canOpen: canOpen, canClose: canClose})
+			}
+			i += n - 1
+		default:
+			text.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r != 0 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
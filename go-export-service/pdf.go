@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"strings"
 
@@ -10,17 +11,17 @@ import (
 
 const marginMM = 19.05
 
-func exportPDF(payload ExportPayload) ([]byte, string, error) {
+func exportPDF(ctx context.Context, payload ExportPayload) ([]byte, string, error) {
 	tpl := getTemplate(payload)
 	var buf bytes.Buffer
 	var err error
 	switch tpl {
 	case "modern":
-		err = renderPDFModern(payload, &buf)
+		err = renderPDFModern(ctx, payload, &buf)
 	case "minimal":
-		err = renderPDFMinimal(payload, &buf)
+		err = renderPDFMinimal(ctx, payload, &buf)
 	default:
-		err = renderPDFClassic(payload, &buf)
+		err = renderPDFClassic(ctx, payload, &buf)
 	}
 	if err != nil {
 		return nil, "", err
@@ -28,7 +29,75 @@ func exportPDF(payload ExportPayload) ([]byte, string, error) {
 	return buf.Bytes(), "application/pdf", nil
 }
 
-func renderPDFClassic(payload ExportPayload, w *bytes.Buffer) error {
+// pdfBuilder lays out a resume onto a fresh document, pushing the first line
+// down by topOffset millimetres below the top margin.
+type pdfBuilder func(ctx context.Context, payload ExportPayload, topOffset float64) (*gofpdf.Fpdf, error)
+
+// buildCenteredPDF runs build with whatever top offset pdfVerticalOffset
+// asks for.
+func buildCenteredPDF(ctx context.Context, payload ExportPayload, build pdfBuilder) (*gofpdf.Fpdf, error) {
+	offset, err := pdfVerticalOffset(ctx, payload, build)
+	if err != nil {
+		return nil, err
+	}
+	return build(ctx, payload, offset)
+}
+
+// pdfVerticalOffset measures the content height with a dry run and returns
+// the top padding that centres it on the page. It is zero unless
+// metadata.vertical_center is set, and also zero in ATS mode or when the
+// content does not fit on a single page.
+func pdfVerticalOffset(ctx context.Context, payload ExportPayload, build pdfBuilder) (float64, error) {
+	if !payload.Metadata.VerticalCenter || payload.Metadata.ATSMode {
+		return 0, nil
+	}
+	pdf, err := build(ctx, payload, 0)
+	if err != nil {
+		return 0, err
+	}
+	if pdf.PageCount() != 1 {
+		return 0, nil
+	}
+	_, pageH := pdf.GetPageSize()
+	free := pageH - marginMM - pdf.GetY()
+	if free <= 0 {
+		return 0, nil
+	}
+	return free / 2, nil
+}
+
+// pdfTheme carries the per-template styling layered over the shared
+// single-column layout. The zero value is the monochrome Classic look.
+type pdfTheme struct {
+	accent     *rgbColor
+	headerBand bool
+}
+
+// modernBandMM is the height of the accent band behind the Modern header.
+const modernBandMM = 30.0
+
+func renderPDFClassic(ctx context.Context, payload ExportPayload, w *bytes.Buffer) error {
+	pdf, err := buildCenteredPDF(ctx, payload, buildPDFClassic)
+	if err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+func buildPDFClassic(ctx context.Context, payload ExportPayload, topOffset float64) (*gofpdf.Fpdf, error) {
+	return buildPDF(ctx, payload, topOffset, pdfTheme{})
+}
+
+func buildPDFModern(ctx context.Context, payload ExportPayload, topOffset float64) (*gofpdf.Fpdf, error) {
+	theme := pdfTheme{headerBand: true}
+	if !payload.Metadata.ATSMode {
+		accent := accentColor(payload.Metadata)
+		theme.accent = &accent
+	}
+	return buildPDF(ctx, payload, topOffset, theme)
+}
+
+func buildPDF(ctx context.Context, payload ExportPayload, topOffset float64, theme pdfTheme) (*gofpdf.Fpdf, error) {
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.SetMargins(marginMM, marginMM, marginMM)
 	pdf.SetAutoPageBreak(true, marginMM)
@@ -37,6 +106,15 @@ func renderPDFClassic(payload ExportPayload, w *bytes.Buffer) error {
 
 	pi := payload.PersonalInfo
 	name := strings.TrimSpace(pi.Name)
+	if theme.headerBand && theme.accent != nil {
+		pageW, _ := pdf.GetPageSize()
+		pdf.SetFillColor(theme.accent.R, theme.accent.G, theme.accent.B)
+		pdf.Rect(0, 0, pageW, modernBandMM, "F")
+		pdf.SetTextColor(255, 255, 255)
+		pdf.SetY(modernBandMM/2 - 7)
+	} else {
+		pdf.SetY(marginMM + topOffset)
+	}
 	if name != "" {
 		pdf.SetFont("Helvetica", "B", 14)
 		pdf.CellFormat(0, 8, name, "", 1, "L", false, 0, "")
@@ -55,105 +133,226 @@ func renderPDFClassic(payload ExportPayload, w *bytes.Buffer) error {
 	if len(contactParts) > 0 {
 		pdf.CellFormat(0, 6, strings.Join(contactParts, " | "), "", 1, "L", false, 0, "")
 	}
-	pdf.Ln(4)
-
-	if payload.Summary != "" {
-		pdf.SetFont("Helvetica", "B", 11)
-		pdf.CellFormat(0, 6, "Summary", "", 1, "L", false, 0, "")
-		pdf.SetFont("Helvetica", "", 10)
-		pdf.MultiCell(0, 5, payload.Summary, "", "L", false)
+	if theme.headerBand && theme.accent != nil {
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetY(modernBandMM + 6 + topOffset)
+	} else {
 		pdf.Ln(4)
 	}
 
-	if len(payload.WorkExperience) > 0 {
-		pdf.SetFont("Helvetica", "B", 11)
-		pdf.CellFormat(0, 6, "Work Experience", "", 1, "L", false, 0, "")
-		pdf.SetFont("Helvetica", "", 10)
-		for _, exp := range payload.WorkExperience {
-			titleCompany := strings.TrimSpace(exp.Title)
-			if exp.Company != "" {
-				titleCompany += " at " + strings.TrimSpace(exp.Company)
-			}
-			pdf.SetFont("Helvetica", "B", 10)
-			pdf.CellFormat(0, 5, titleCompany, "", 1, "L", false, 0, "")
-			pdf.SetFont("Helvetica", "", 9)
-			dateStr := exp.StartDate
-			if exp.EndDate != "" {
-				dateStr += " - " + exp.EndDate
+	if err := renderSections(ctx, payload.Metadata, sectionRenderers{
+		objective: func() {
+			if objective := objectiveText(payload); objective != "" {
+				pdfSectionHeading(pdf, theme, "Objective")
+				pdf.MultiCell(0, 5, objective, "", "L", false)
+				pdf.Ln(4)
 			}
-			if dateStr != "" {
-				pdf.CellFormat(0, 4, dateStr, "", 1, "L", false, 0, "")
+		},
+		summary: func() {
+			if summary := summaryText(payload); summary != "" {
+				pdfSectionHeading(pdf, theme, "Summary")
+				writeInlineMarkdown(pdf, 5, summary)
+				pdf.Ln(9)
 			}
-			for _, b := range exp.Bullets {
-				if b == "" {
-					continue
+		},
+		experience: func() {
+			if len(payload.WorkExperience) > 0 {
+				pdfSectionHeading(pdf, theme, "Work Experience")
+				for _, exp := range payload.WorkExperience {
+					if ctx.Err() != nil {
+						return
+					}
+					titleCompany := strings.TrimSpace(exp.Title)
+					if exp.Company != "" {
+						titleCompany += " at " + strings.TrimSpace(exp.Company)
+					}
+					pdf.SetFont("Helvetica", "B", 10)
+					pdf.CellFormat(0, 5, titleCompany, "", 1, "L", false, 0, "")
+					pdf.SetFont("Helvetica", "", 9)
+					dateStr := exp.StartDate
+					if exp.EndDate != "" {
+						dateStr += " - " + exp.EndDate
+					}
+					if dateStr != "" {
+						pdf.CellFormat(0, 4, dateStr, "", 1, "L", false, 0, "")
+					}
+					for _, b := range exp.Bullets {
+						if ctx.Err() != nil {
+							return
+						}
+						if b == "" {
+							continue
+						}
+						pdf.CellFormat(5, 4, "-", "", 0, "L", false, 0, "")
+						pdf.SetLeftMargin(marginMM + 5)
+						writeInlineMarkdown(pdf, 4, b)
+						pdf.SetLeftMargin(marginMM)
+						pdf.Ln(4)
+					}
+					pdf.Ln(2)
 				}
-				pdf.CellFormat(5, 4, "-", "", 0, "L", false, 0, "")
-				pdf.MultiCell(0, 4, b, "", "L", false)
+				pdf.Ln(2)
 			}
-			pdf.Ln(2)
-		}
-		pdf.Ln(2)
-	}
-
-	if len(payload.Education) > 0 {
-		pdf.SetFont("Helvetica", "B", 11)
-		pdf.CellFormat(0, 6, "Education", "", 1, "L", false, 0, "")
-		pdf.SetFont("Helvetica", "", 10)
-		for _, edu := range payload.Education {
-			line := strings.TrimSpace(edu.Degree)
-			if edu.Field != "" {
-				line += " in " + strings.TrimSpace(edu.Field)
+		},
+		education: func() {
+			if len(payload.Education) > 0 {
+				pdfSectionHeading(pdf, theme, "Education")
+				for _, edu := range payload.Education {
+					line := strings.TrimSpace(edu.Degree)
+					if edu.Field != "" {
+						line += " in " + strings.TrimSpace(edu.Field)
+					}
+					if edu.School != "" {
+						line += ", " + strings.TrimSpace(edu.School)
+					}
+					if line != "" {
+						pdf.CellFormat(0, 5, line, "", 1, "L", false, 0, "")
+					}
+				}
+				pdf.Ln(2)
 			}
-			if edu.School != "" {
-				line += ", " + strings.TrimSpace(edu.School)
+		},
+		skills: func() {
+			topSkills := topSkillsList(payload)
+			if len(payload.Skills) > 0 || len(topSkills) > 0 {
+				pdfSectionHeading(pdf, theme, "Skills")
+				if len(topSkills) > 0 {
+					switch {
+					case payload.Metadata.ATSMode:
+						pdf.MultiCell(0, 5, "Top Skills: "+strings.Join(topSkills, ", "), "", "L", false)
+					case theme.accent != nil:
+						pdfSkillChips(pdf, *theme.accent, topSkills)
+					default:
+						pdf.SetFont("Helvetica", "B", 10)
+						pdf.MultiCell(0, 5, strings.Join(topSkills, ", "), "", "L", false)
+						pdf.SetFont("Helvetica", "", 10)
+					}
+				}
+				for cat, skills := range payload.Skills {
+					if cat == "" {
+						cat = "Other"
+					}
+					var parts []string
+					for _, s := range skills {
+						if s != "" {
+							parts = append(parts, strings.TrimSpace(s))
+						}
+					}
+					if len(parts) > 0 {
+						pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", cat, strings.Join(parts, ", ")), "", 1, "L", false, 0, "")
+					}
+				}
+				pdf.Ln(2)
 			}
-			if line != "" {
-				pdf.CellFormat(0, 5, line, "", 1, "L", false, 0, "")
+		},
+		certifications: func() {
+			if len(payload.Certifications) > 0 {
+				pdfSectionHeading(pdf, theme, "Certifications")
+				for _, c := range payload.Certifications {
+					if c != "" {
+						pdf.CellFormat(0, 5, "- "+strings.TrimSpace(c), "", 1, "L", false, 0, "")
+					}
+				}
+				pdf.Ln(2)
 			}
-		}
-		pdf.Ln(2)
+		},
+	}); err != nil {
+		return nil, err
 	}
+	return pdf, nil
+}
 
-	if len(payload.Skills) > 0 {
-		pdf.SetFont("Helvetica", "B", 11)
-		pdf.CellFormat(0, 6, "Skills", "", 1, "L", false, 0, "")
-		pdf.SetFont("Helvetica", "", 10)
-		for cat, skills := range payload.Skills {
-			if cat == "" {
-				cat = "Other"
-			}
-			var parts []string
-			for _, s := range skills {
-				if s != "" {
-					parts = append(parts, strings.TrimSpace(s))
-				}
-			}
-			if len(parts) > 0 {
-				pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", cat, strings.Join(parts, ", ")), "", 1, "L", false, 0, "")
+// pdfSkillChips lays skills out as filled accent-coloured pills, wrapping to
+// a new row when the next chip would cross the right margin. A skill too
+// long for any row gets a full-width block of its own that wraps its text.
+func pdfSkillChips(pdf *gofpdf.Fpdf, accent rgbColor, skills []string) {
+	const chipH, padX, gap = 6.5, 2.5, 2.0
+	pageW, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	contentW := pageW - left - right
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.SetFillColor(accent.R, accent.G, accent.B)
+	pdf.SetTextColor(255, 255, 255)
+	x, y := left, pdf.GetY()
+	for _, s := range skills {
+		chipW := pdf.GetStringWidth(s) + 2*padX
+		if chipW > contentW {
+			if x > left {
+				y += chipH + gap
 			}
+			pdf.SetXY(left, y)
+			pdf.MultiCell(contentW, chipH, s, "", "C", true)
+			x, y = left, pdf.GetY()+gap
+			continue
 		}
-		pdf.Ln(2)
+		if x > left && x+chipW > pageW-right {
+			x, y = left, y+chipH+gap
+		}
+		pdf.SetXY(x, y)
+		pdf.CellFormat(chipW, chipH, s, "", 0, "C", true, 0, "")
+		x, y = x+chipW+gap, pdf.GetY()
+	}
+	if x > left {
+		y += chipH + gap
 	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(left, y)
+	pdf.SetFont("Helvetica", "", 10)
+}
 
-	if len(payload.Certifications) > 0 {
-		pdf.SetFont("Helvetica", "B", 11)
-		pdf.CellFormat(0, 6, "Certifications", "", 1, "L", false, 0, "")
-		pdf.SetFont("Helvetica", "", 10)
-		for _, c := range payload.Certifications {
-			if c != "" {
-				pdf.CellFormat(0, 5, "- "+strings.TrimSpace(c), "", 1, "L", false, 0, "")
-			}
+// writeInlineMarkdown flows s from the current position, switching between
+// Helvetica bold/italic and Courier as the Markdown spans require. Wrapped
+// lines return to the left margin; the caller ends the line.
+func writeInlineMarkdown(pdf *gofpdf.Fpdf, lineHeight float64, s string) {
+	size, _ := pdf.GetFontSize()
+	for _, span := range parseInlineMarkdown(s) {
+		family, style := "Helvetica", ""
+		if span.Code {
+			family = "Courier"
 		}
+		if span.Bold {
+			style += "B"
+		}
+		if span.Italic {
+			style += "I"
+		}
+		pdf.SetFont(family, style, size)
+		pdf.Write(lineHeight, span.Text)
 	}
+	pdf.SetFont("Helvetica", "", size)
+}
 
+func renderPDFModern(ctx context.Context, payload ExportPayload, w *bytes.Buffer) error {
+	pdf, err := buildCenteredPDF(ctx, payload, buildPDFModern)
+	if err != nil {
+		return err
+	}
 	return pdf.Output(w)
 }
 
-func renderPDFModern(payload ExportPayload, w *bytes.Buffer) error {
-	return renderPDFClassic(payload, w)
+// pdfSectionHeading writes a section title and leaves the body font set.
+// Themed headings are drawn in the accent colour with a rule underneath.
+func pdfSectionHeading(pdf *gofpdf.Fpdf, theme pdfTheme, title string) {
+	pdf.SetFont("Helvetica", "B", 11)
+	if theme.accent == nil {
+		pdf.CellFormat(0, 6, title, "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		return
+	}
+	pdf.SetTextColor(theme.accent.R, theme.accent.G, theme.accent.B)
+	pdf.CellFormat(0, 6, title, "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pageW, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	y := pdf.GetY()
+	pdf.SetDrawColor(theme.accent.R, theme.accent.G, theme.accent.B)
+	pdf.SetLineWidth(0.4)
+	pdf.Line(left, y, pageW-right, y)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.Ln(1.5)
+	pdf.SetFont("Helvetica", "", 10)
 }
 
-func renderPDFMinimal(payload ExportPayload, w *bytes.Buffer) error {
-	return renderPDFClassic(payload, w)
+func renderPDFMinimal(ctx context.Context, payload ExportPayload, w *bytes.Buffer) error {
+	return renderPDFClassic(ctx, payload, w)
 }
@@ -1,22 +1,27 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html"
 	"strings"
 )
 
-func exportPreview(payload ExportPayload) ([]byte, string, error) {
+func exportPreview(ctx context.Context, payload ExportPayload) ([]byte, string, error) {
 	tpl := getTemplate(payload)
 	var sb strings.Builder
+	var err error
 	switch tpl {
 	case "modern":
-		renderHTMLModern(payload, &sb)
+		err = renderHTMLModern(ctx, payload, &sb)
 	case "minimal":
-		renderHTMLMinimal(payload, &sb)
+		err = renderHTMLMinimal(ctx, payload, &sb)
 	default:
-		renderHTMLClassic(payload, &sb)
+		err = renderHTMLClassic(ctx, payload, &sb)
+	}
+	if err != nil {
+		return nil, "", err
 	}
 	out := map[string]string{"html": sb.String()}
 	data, err := json.Marshal(out)
@@ -26,12 +31,22 @@ func exportPreview(payload ExportPayload) ([]byte, string, error) {
 	return data, "application/json", nil
 }
 
-func renderHTMLClassic(payload ExportPayload, w *strings.Builder) {
+func renderHTMLClassic(ctx context.Context, payload ExportPayload, w *strings.Builder) error {
+	return renderHTML(ctx, payload, w, "")
+}
+
+// renderHTML writes the shared single-column preview. headingColor is a CSS
+// colour applied to the name and section headings, or "" for the default.
+func renderHTML(ctx context.Context, payload ExportPayload, w *strings.Builder, headingColor string) error {
+	headingStyle := ""
+	if headingColor != "" {
+		headingStyle = "color:" + headingColor + ";"
+	}
 	w.WriteString(`<div style="font-family:Helvetica,Arial,sans-serif;max-width:700px;margin:0 auto;padding:1rem;font-size:14px;">`)
 	pi := payload.PersonalInfo
 	name := strings.TrimSpace(pi.Name)
 	if name != "" {
-		w.WriteString(fmt.Sprintf("<h1 style=\"margin:0 0 0.5rem 0;font-size:1.5rem;\">%s</h1>", html.EscapeString(name)))
+		w.WriteString(fmt.Sprintf("<h1 style=\"margin:0 0 0.5rem 0;font-size:1.5rem;%s\">%s</h1>", headingStyle, html.EscapeString(name)))
 	}
 	contactParts := []string{}
 	if pi.Email != "" {
@@ -46,82 +61,152 @@ func renderHTMLClassic(payload ExportPayload, w *strings.Builder) {
 	if len(contactParts) > 0 {
 		w.WriteString(fmt.Sprintf("<p style=\"margin:0 0 1rem 0;color:#444;\">%s</p>", strings.Join(contactParts, " | ")))
 	}
-	if payload.Summary != "" {
-		w.WriteString("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;\">Summary</h2>")
-		w.WriteString(fmt.Sprintf("<p style=\"margin:0;\">%s</p>", html.EscapeString(payload.Summary)))
-	}
-	if len(payload.WorkExperience) > 0 {
-		w.WriteString("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;\">Work Experience</h2>")
-		for _, exp := range payload.WorkExperience {
-			titleCompany := html.EscapeString(strings.TrimSpace(exp.Title))
-			if exp.Company != "" {
-				titleCompany += " at " + html.EscapeString(strings.TrimSpace(exp.Company))
+	if err := renderSections(ctx, payload.Metadata, sectionRenderers{
+		objective: func() {
+			if objective := objectiveText(payload); objective != "" {
+				w.WriteString(fmt.Sprintf("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;%s\">Objective</h2>", headingStyle))
+				w.WriteString(fmt.Sprintf("<p style=\"margin:0;\">%s</p>", html.EscapeString(objective)))
 			}
-			w.WriteString(fmt.Sprintf("<p style=\"margin:0.25rem 0;font-weight:bold;\">%s</p>", titleCompany))
-			dateStr := exp.StartDate
-			if exp.EndDate != "" {
-				dateStr += " - " + exp.EndDate
+		},
+		summary: func() {
+			if summary := summaryText(payload); summary != "" {
+				w.WriteString(fmt.Sprintf("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;%s\">Summary</h2>", headingStyle))
+				w.WriteString(fmt.Sprintf("<p style=\"margin:0;\">%s</p>", inlineMarkdownHTML(summary)))
 			}
-			if dateStr != "" {
-				w.WriteString(fmt.Sprintf("<p style=\"margin:0 0 0.25rem 0;font-size:0.9rem;color:#555;\">%s</p>", html.EscapeString(dateStr)))
-			}
-			w.WriteString("<ul style=\"margin:0 0 0.5rem 1rem;padding:0;\">")
-			for _, b := range exp.Bullets {
-				if b != "" {
-					w.WriteString(fmt.Sprintf("<li>%s</li>", html.EscapeString(b)))
+		},
+		experience: func() {
+			if len(payload.WorkExperience) > 0 {
+				w.WriteString(fmt.Sprintf("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;%s\">Work Experience</h2>", headingStyle))
+				for _, exp := range payload.WorkExperience {
+					if ctx.Err() != nil {
+						return
+					}
+					titleCompany := html.EscapeString(strings.TrimSpace(exp.Title))
+					if exp.Company != "" {
+						titleCompany += " at " + html.EscapeString(strings.TrimSpace(exp.Company))
+					}
+					w.WriteString(fmt.Sprintf("<p style=\"margin:0.25rem 0;font-weight:bold;\">%s</p>", titleCompany))
+					dateStr := exp.StartDate
+					if exp.EndDate != "" {
+						dateStr += " - " + exp.EndDate
+					}
+					if dateStr != "" {
+						w.WriteString(fmt.Sprintf("<p style=\"margin:0 0 0.25rem 0;font-size:0.9rem;color:#555;\">%s</p>", html.EscapeString(dateStr)))
+					}
+					w.WriteString("<ul style=\"margin:0 0 0.5rem 1rem;padding:0;\">")
+					for _, b := range exp.Bullets {
+						if ctx.Err() != nil {
+							return
+						}
+						if b != "" {
+							w.WriteString(fmt.Sprintf("<li>%s</li>", inlineMarkdownHTML(b)))
+						}
+					}
+					w.WriteString("</ul>")
 				}
 			}
-			w.WriteString("</ul>")
-		}
-	}
-	if len(payload.Education) > 0 {
-		w.WriteString("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;\">Education</h2>")
-		for _, edu := range payload.Education {
-			line := html.EscapeString(strings.TrimSpace(edu.Degree))
-			if edu.Field != "" {
-				line += " in " + html.EscapeString(strings.TrimSpace(edu.Field))
-			}
-			if edu.School != "" {
-				line += ", " + html.EscapeString(strings.TrimSpace(edu.School))
-			}
-			if line != "" {
-				w.WriteString(fmt.Sprintf("<p style=\"margin:0.25rem 0;\">%s</p>", line))
-			}
-		}
-	}
-	if len(payload.Skills) > 0 {
-		w.WriteString("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;\">Skills</h2>")
-		for cat, skills := range payload.Skills {
-			if cat == "" {
-				cat = "Other"
+		},
+		education: func() {
+			if len(payload.Education) > 0 {
+				w.WriteString(fmt.Sprintf("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;%s\">Education</h2>", headingStyle))
+				for _, edu := range payload.Education {
+					line := html.EscapeString(strings.TrimSpace(edu.Degree))
+					if edu.Field != "" {
+						line += " in " + html.EscapeString(strings.TrimSpace(edu.Field))
+					}
+					if edu.School != "" {
+						line += ", " + html.EscapeString(strings.TrimSpace(edu.School))
+					}
+					if line != "" {
+						w.WriteString(fmt.Sprintf("<p style=\"margin:0.25rem 0;\">%s</p>", line))
+					}
+				}
 			}
-			var parts []string
-			for _, s := range skills {
-				if s != "" {
-					parts = append(parts, html.EscapeString(strings.TrimSpace(s)))
+		},
+		skills: func() {
+			topSkills := topSkillsList(payload)
+			if len(payload.Skills) > 0 || len(topSkills) > 0 {
+				w.WriteString(fmt.Sprintf("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;%s\">Skills</h2>", headingStyle))
+				if len(topSkills) > 0 {
+					var parts []string
+					for _, s := range topSkills {
+						parts = append(parts, html.EscapeString(s))
+					}
+					switch {
+					case payload.Metadata.ATSMode:
+						w.WriteString(fmt.Sprintf("<p style=\"margin:0.25rem 0;\">Top Skills: %s</p>", strings.Join(parts, ", ")))
+					case headingColor != "":
+						w.WriteString("<p style=\"margin:0.25rem 0 0.5rem 0;\">")
+						for _, s := range parts {
+							w.WriteString(fmt.Sprintf("<span style=\"display:inline-block;margin:0 0.35rem 0.35rem 0;padding:0.15rem 0.6rem;border-radius:999px;background:%s;color:#fff;font-weight:bold;font-size:1rem;\">%s</span>", headingColor, s))
+						}
+						w.WriteString("</p>")
+					default:
+						w.WriteString(fmt.Sprintf("<p style=\"margin:0.25rem 0;\"><strong>%s</strong></p>", strings.Join(parts, ", ")))
+					}
+				}
+				for cat, skills := range payload.Skills {
+					if cat == "" {
+						cat = "Other"
+					}
+					var parts []string
+					for _, s := range skills {
+						if s != "" {
+							parts = append(parts, html.EscapeString(strings.TrimSpace(s)))
+						}
+					}
+					if len(parts) > 0 {
+						w.WriteString(fmt.Sprintf("<p style=\"margin:0.25rem 0;\">%s: %s</p>", html.EscapeString(cat), strings.Join(parts, ", ")))
+					}
 				}
 			}
-			if len(parts) > 0 {
-				w.WriteString(fmt.Sprintf("<p style=\"margin:0.25rem 0;\">%s: %s</p>", html.EscapeString(cat), strings.Join(parts, ", ")))
+		},
+		certifications: func() {
+			if len(payload.Certifications) > 0 {
+				w.WriteString(fmt.Sprintf("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;%s\">Certifications</h2><ul style=\"margin:0 0 0 1rem;padding:0;\">", headingStyle))
+				for _, c := range payload.Certifications {
+					if c != "" {
+						w.WriteString(fmt.Sprintf("<li>%s</li>", html.EscapeString(strings.TrimSpace(c))))
+					}
+				}
+				w.WriteString("</ul>")
 			}
-		}
+		},
+	}); err != nil {
+		return err
 	}
-	if len(payload.Certifications) > 0 {
-		w.WriteString("<h2 style=\"font-size:1.1rem;margin:1rem 0 0.25rem 0;\">Certifications</h2><ul style=\"margin:0 0 0 1rem;padding:0;\">")
-		for _, c := range payload.Certifications {
-			if c != "" {
-				w.WriteString(fmt.Sprintf("<li>%s</li>", html.EscapeString(strings.TrimSpace(c))))
-			}
+	w.WriteString("</div>")
+	return nil
+}
+
+// inlineMarkdownHTML renders the Markdown spans in s as <strong>, <em> and
+// <code>, escaping the text itself.
+func inlineMarkdownHTML(s string) string {
+	var sb strings.Builder
+	for _, span := range parseInlineMarkdown(s) {
+		text := html.EscapeString(span.Text)
+		if span.Code {
+			text = "<code>" + text + "</code>"
+		}
+		if span.Italic {
+			text = "<em>" + text + "</em>"
 		}
-		w.WriteString("</ul>")
+		if span.Bold {
+			text = "<strong>" + text + "</strong>"
+		}
+		sb.WriteString(text)
 	}
-	w.WriteString("</div>")
+	return sb.String()
 }
 
-func renderHTMLModern(payload ExportPayload, w *strings.Builder) {
-	renderHTMLClassic(payload, w)
+func renderHTMLModern(ctx context.Context, payload ExportPayload, w *strings.Builder) error {
+	headingColor := ""
+	if !payload.Metadata.ATSMode {
+		headingColor = "#" + accentColor(payload.Metadata).Hex()
+	}
+	return renderHTML(ctx, payload, w, headingColor)
 }
 
-func renderHTMLMinimal(payload ExportPayload, w *strings.Builder) {
-	renderHTMLClassic(payload, w)
+func renderHTMLMinimal(ctx context.Context, payload ExportPayload, w *strings.Builder) error {
+	return renderHTMLClassic(ctx, payload, w)
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// defaultSectionOrder is the layout used when metadata.section_order is empty.
+var defaultSectionOrder = []string{"objective", "summary", "experience", "education", "skills", "certifications"}
+
+// sectionOrder returns the section keys to render, in order. Unknown and
+// repeated keys are dropped; a list with no known keys means the default
+// order, so a typo cannot produce a header-only resume.
+func sectionOrder(meta ExportMetadata) []string {
+	seen := map[string]bool{}
+	var order []string
+	for _, key := range meta.SectionOrder {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if seen[key] {
+			continue
+		}
+		for _, known := range defaultSectionOrder {
+			if key == known {
+				seen[key] = true
+				order = append(order, key)
+				break
+			}
+		}
+	}
+	if len(order) == 0 {
+		return defaultSectionOrder
+	}
+	return order
+}
+
+// rendersSection reports whether key is part of the effective section order.
+func rendersSection(meta ExportMetadata, key string) bool {
+	for _, k := range sectionOrder(meta) {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionRenderers holds one format's render block per section key. PDF,
+// DOCX and preview each fill in the same fields and share renderSections,
+// so the formats cannot disagree on ordering.
+type sectionRenderers struct {
+	objective      func()
+	summary        func()
+	experience     func()
+	education      func()
+	skills         func()
+	certifications func()
+}
+
+// renderSections runs the blocks in the order the metadata asks for,
+// bailing out between sections once ctx is done.
+func renderSections(ctx context.Context, meta ExportMetadata, r sectionRenderers) error {
+	blocks := map[string]func(){
+		"objective":      r.objective,
+		"summary":        r.summary,
+		"experience":     r.experience,
+		"education":      r.education,
+		"skills":         r.skills,
+		"certifications": r.certifications,
+	}
+	for _, key := range sectionOrder(meta) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if render := blocks[key]; render != nil {
+			render()
+		}
+	}
+	return ctx.Err()
+}
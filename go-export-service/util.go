@@ -1,11 +1,25 @@
 package main
 
-import "strconv"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func parseInt(s string) (int, error) {
 	return strconv.Atoi(s)
 }
 
+// parseDuration accepts a Go duration string ("15s", "1m") or a bare number
+// of seconds.
+func parseDuration(s string) (time.Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	return time.ParseDuration(s)
+}
+
 func getTemplate(payload ExportPayload) string {
 	if payload.Metadata.ATSMode {
 		return "classic"
@@ -16,3 +30,72 @@ func getTemplate(payload ExportPayload) string {
 	}
 	return "classic"
 }
+
+// objectiveText returns the trimmed objective statement, or "" when blank.
+func objectiveText(payload ExportPayload) string {
+	return strings.TrimSpace(payload.Objective)
+}
+
+// summaryText returns the trimmed summary, or "" when it is blank or the
+// metadata asks for the objective to stand in for it. The objective only
+// stands in when it is actually rendered, i.e. listed in the section order.
+func summaryText(payload ExportPayload) string {
+	if payload.Metadata.ObjectiveReplacesSummary && objectiveText(payload) != "" && rendersSection(payload.Metadata, "objective") {
+		return ""
+	}
+	return strings.TrimSpace(payload.Summary)
+}
+
+// topSkillsList returns the trimmed, non-empty top skills in order.
+func topSkillsList(payload ExportPayload) []string {
+	var out []string
+	for _, s := range payload.TopSkills {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// defaultAccentHex is the Modern accent used when metadata.accent_color is
+// missing or unparseable.
+const defaultAccentHex = "#2563eb"
+
+type rgbColor struct {
+	R, G, B int
+}
+
+// Hex returns the colour as six uppercase hex digits without a leading "#",
+// the form DOCX run colours expect.
+func (c rgbColor) Hex() string {
+	return fmt.Sprintf("%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// parseHexColor accepts "#rgb", "rgb", "#rrggbb" or "rrggbb".
+func parseHexColor(s string) (rgbColor, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return rgbColor{}, false
+	}
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return rgbColor{}, false
+	}
+	return rgbColor{R: int(n >> 16), G: int(n >> 8 & 0xff), B: int(n & 0xff)}, true
+}
+
+// accentColor resolves metadata.accent_color, falling back to the default
+// accent rather than failing the export. ATS mode is always monochrome.
+func accentColor(meta ExportMetadata) rgbColor {
+	if meta.ATSMode {
+		return rgbColor{}
+	}
+	if c, ok := parseHexColor(meta.AccentColor); ok {
+		return c
+	}
+	c, _ := parseHexColor(defaultAccentHex)
+	return c
+}